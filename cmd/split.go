@@ -22,15 +22,28 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 
 	"github.com/brentp/xopen"
+	"github.com/shenwei356/bio/seq"
 	"github.com/shenwei356/bio/seqio/fasta"
+	"github.com/shenwei356/bio/seqio/fastq"
 	"github.com/shenwei356/util/byteutil"
 	"github.com/spf13/cobra"
 )
 
+// seqRecord is a format-agnostic record used throughout splitCmd so that the
+// -s/-p/-i/-r modes don't need to care whether the input was FASTA or FASTQ.
+// Qual is nil for FASTA records.
+type seqRecord struct {
+	ID   []byte
+	Seq  []byte
+	Qual []byte
+}
+
 // splitCmd represents the seq command
 var splitCmd = &cobra.Command{
 	Use:   "split",
@@ -38,6 +51,9 @@ var splitCmd = &cobra.Command{
 	Long: `split sequences into files by name ID, sub sequence of given region,
 part size or number of parts.
 
+Both FASTA and FASTQ are supported, the format is auto-detected from the
+first non-empty byte of the input ('>' for FASTA, '@' for FASTQ).
+
 The definition of region is 1-based and with some custom design.
 
 Examples:
@@ -87,20 +103,164 @@ Examples:
 		}
 		dryRun := getFlagBool(cmd, "dry-run")
 
+		byMeanQual := getFlagBool(cmd, "by-mean-qual")
+		minQual := getFlagFloat64(cmd, "min-qual")
+
+		byBasesStr := getFlagString(cmd, "by-bases")
+		noSplitSeq := getFlagBool(cmd, "no-split-seq")
+
+		byFrame := getFlagBool(cmd, "by-frame")
+		transTable := getFlagInt(cmd, "trans-table")
+		minOrf := getFlagInt(cmd, "min-orf")
+		longestOrfOnly := getFlagBool(cmd, "longest-orf-only")
+
+		outCompress := getFlagString(cmd, "out-compress")
+		switch outCompress {
+		case "none", "gz", "bgzf", "zst":
+		default:
+			checkError(fmt.Errorf("invalid --out-compress: %s, must be one of none, gz, bgzf, zst", outCompress))
+		}
+		outDir := getFlagString(cmd, "out-dir")
+		manifestFile := getFlagString(cmd, "manifest")
+
 		outfh, err := xopen.Wopen(outFile)
 		checkError(err)
 		defer outfh.Close()
 
 		file := files[0]
-		var fileName, fileExt string
 		if file == "-" {
-			fileName, fileExt = "stdin", ".fasta"
+			// Sniffing the format and scanning byte offsets both need to
+			// read the input more than once, which a stdin pipe can't do.
+			// Materialize it to a temp file once and operate on that instead.
+			tmp, err := stdinToTempFile()
+			checkError(err)
+			defer os.Remove(tmp)
+			file = tmp
+		}
+
+		var fileName, fileExt string
+		if files[0] == "-" {
+			fileName = "stdin"
 		} else {
 			fileName, fileExt = filepathTrimExtension(file)
 		}
 
+		isFastq, err := detectIsFastq(file)
+		checkError(err)
+		if isFastq {
+			fileExt = fastqExt(file)
+		} else if files[0] == "-" {
+			fileExt = ".fasta"
+		}
+
+		if byMeanQual && !isFastq {
+			checkError(fmt.Errorf("flag --by-mean-qual can only be used with FASTQ input"))
+		}
+		if minQual > 0 && !isFastq {
+			checkError(fmt.Errorf("flag --min-qual can only be used with FASTQ input"))
+		}
+
+		mc := newManifestCollector(outDir, outCompress, dryRun)
+		defer func() {
+			checkError(mc.writeManifest(manifestFile))
+		}()
+
 		var outfile string
 
+		if byMeanQual {
+			if !quiet {
+				log.Infof("split by rounded mean quality")
+			}
+			if !quiet {
+				log.Info("read sequences ...")
+			}
+			allRecords, err := getAllRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+			checkError(err)
+			if !quiet {
+				log.Infof("read %d sequences", len(allRecords))
+			}
+
+			recordsByQual := make(map[int][]*seqRecord)
+			for _, record := range allRecords {
+				q := int(meanQual(record.Qual) + 0.5)
+				recordsByQual[q] = append(recordsByQual[q], record)
+			}
+
+			for q, records := range recordsByQual {
+				outfile = fmt.Sprintf("%s.part_Q%d%s", fileName, q, fileExt)
+				mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
+			}
+			return
+		}
+
+		if minQual > 0 {
+			if !quiet {
+				log.Infof("route reads with mean quality below %v to .lowqual, split the rest", minQual)
+			}
+			allRecords, err := getAllRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+			checkError(err)
+			if !quiet {
+				log.Infof("read %d sequences", len(allRecords))
+			}
+
+			good := []*seqRecord{}
+			low := []*seqRecord{}
+			for _, record := range allRecords {
+				if meanQual(record.Qual) < minQual {
+					low = append(low, record)
+				} else {
+					good = append(good, record)
+				}
+			}
+			if len(low) > 0 {
+				outfile = fmt.Sprintf("%s.lowqual%s", fileName, fileExt)
+				mc.writeSeqs(low, outfile, lineWidth, quiet, isFastq)
+			}
+
+			splitBySizeOrPart(mc, good, fileName, fileExt, size, part, lineWidth, quiet, isFastq)
+			return
+		}
+
+		if byFrame {
+			if !quiet {
+				log.Infof("split by translated reading frames, table %d", transTable)
+			}
+			if !quiet {
+				log.Info("read sequences ...")
+			}
+			allRecords, err := getAllRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+			checkError(err)
+			if !quiet {
+				log.Infof("read %d sequences", len(allRecords))
+			}
+
+			checkError(splitByFrame(mc, allRecords, fileName, transTable, minOrf, longestOrfOnly, lineWidth, quiet))
+			return
+		}
+
+		if byBasesStr != "" {
+			sizeBases, err := parseBaseSize(byBasesStr)
+			checkError(err)
+			if sizeBases <= 0 {
+				checkError(fmt.Errorf("value of flag --by-bases should be greater than 0: %s", byBasesStr))
+			}
+			if !quiet {
+				log.Infof("split into parts of %d bases", sizeBases)
+			}
+
+			if !quiet {
+				log.Info("read sequences ...")
+			}
+			allRecords, err := getAllRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+			checkError(err)
+			if !quiet {
+				log.Infof("read %d sequences", len(allRecords))
+			}
+
+			splitByBases(mc, allRecords, fileName, fileExt, sizeBases, noSplitSeq, lineWidth, quiet, isFastq)
+			return
+		}
+
 		if size > 0 {
 			if !quiet {
 				log.Infof("split into %d seqs per file", size)
@@ -110,26 +270,22 @@ Examples:
 			}
 
 			i := 1
-			records := []*fasta.FastaRecord{}
+			records := []*seqRecord{}
 
-			fastaReader, err := fasta.NewFastaReader(alphabet, file, chunkSize, threads, idRegexp)
-			checkError(err)
-
-			for chunk := range fastaReader.Ch {
-				checkError(chunk.Err)
-				for _, record := range chunk.Data {
-					records = append(records, record)
-					if len(records) == size {
-						outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
-						writeSeqs(records, outfile, lineWidth, quiet, dryRun)
-						i++
-						records = []*fasta.FastaRecord{}
-					}
+			recordCh, errCh := streamRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+			for record := range recordCh {
+				records = append(records, record)
+				if len(records) == size {
+					outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
+					mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
+					i++
+					records = []*seqRecord{}
 				}
 			}
+			checkError(<-errCh)
 			if len(records) > 0 {
 				outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
-				writeSeqs(records, outfile, lineWidth, quiet, dryRun)
+				mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
 			}
 			return
 		}
@@ -146,14 +302,13 @@ Examples:
 				if !quiet {
 					log.Info("first pass: get seq number")
 				}
-				names, err := fasta.GetSeqNames(file)
+				n, err := countRecords(file, alphabet, isFastq)
 				checkError(err)
 
 				if !quiet {
-					log.Infof("seq number: %d", len(names))
+					log.Infof("seq number: %d", n)
 				}
 
-				n := len(names)
 				if n%part > 0 {
 					size = int(n/part) + 1
 					if n%size == 0 {
@@ -169,62 +324,48 @@ Examples:
 					log.Info("second pass: read and split")
 				}
 				i := 1
-				records := []*fasta.FastaRecord{}
-				fastaReader, err := fasta.NewFastaReader(alphabet, file, chunkSize, threads, idRegexp)
-				checkError(err)
-				for chunk := range fastaReader.Ch {
-					checkError(chunk.Err)
-					for _, record := range chunk.Data {
-						records = append(records, record)
-						if len(records) == size {
-							outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
-							writeSeqs(records, outfile, lineWidth, quiet, dryRun)
-							i++
-							records = []*fasta.FastaRecord{}
-						}
-					}
-				}
-				if len(records) > 0 {
-					outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
-					writeSeqs(records, outfile, lineWidth, quiet, dryRun)
-				}
-			} else {
-				i := 1
-				records := []*fasta.FastaRecord{}
-
-				if !quiet {
-					log.Info("read sequences ...")
-				}
-				allRecords, err := fasta.GetSeqs(file, alphabet, chunkSize, threads, idRegexp)
-				checkError(err)
-				if !quiet {
-					log.Infof("read %d sequences", len(allRecords))
-				}
-
-				n := len(allRecords)
-				if n%part > 0 {
-					size = int(n/part) + 1
-					if n%size == 0 {
-						if !quiet {
-							log.Infof("corrected: split into %d parts", n/size)
-						}
-					}
-				} else {
-					size = int(n / part)
-				}
-
-				for _, record := range allRecords {
+				records := []*seqRecord{}
+				recordCh, errCh := streamRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+				for record := range recordCh {
 					records = append(records, record)
 					if len(records) == size {
 						outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
-						writeSeqs(records, outfile, lineWidth, quiet, dryRun)
+						mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
 						i++
-						records = []*fasta.FastaRecord{}
+						records = []*seqRecord{}
 					}
 				}
+				checkError(<-errCh)
 				if len(records) > 0 {
 					outfile = fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
-					writeSeqs(records, outfile, lineWidth, quiet, dryRun)
+					mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
+				}
+			} else {
+				strategy := getFlagString(cmd, "part-strategy")
+				shaped := outCompress != "none" || outDir != "" || manifestFile != ""
+				switch {
+				case strategy == "sequential" && isSeekableFile(file) && !shaped:
+					if !quiet {
+						log.Info("single-pass split by byte offsets (bounded memory)")
+					}
+					checkError(splitByPartOffsets(file, fileName, fileExt, part, quiet, dryRun, isFastq))
+				case strategy == "sequential":
+					// stdin/gzip, or --out-compress/--out-dir/--manifest requested:
+					// the zero-copy offset splitter needs seekable raw bytes on
+					// disk, which neither case gives it. Delegate to the
+					// bounded-memory round-robin dispatcher instead of buffering
+					// every record in memory.
+					if !quiet {
+						log.Info("single-pass round-robin split (bounded memory)")
+					}
+					checkError(splitByPartDispatch(mc, file, alphabet, chunkSize, threads, idRegexp, fileName, fileExt, part, lineWidth, quiet, isFastq, "roundrobin"))
+				case strategy == "roundrobin" || strategy == "balanced-bases":
+					if !quiet {
+						log.Infof("single-pass %s split (bounded memory)", strategy)
+					}
+					checkError(splitByPartDispatch(mc, file, alphabet, chunkSize, threads, idRegexp, fileName, fileExt, part, lineWidth, quiet, isFastq, strategy))
+				default:
+					checkError(fmt.Errorf("invalid --part-strategy: %s, must be one of sequential, roundrobin, balanced-bases", strategy))
 				}
 			}
 			return
@@ -240,27 +381,24 @@ Examples:
 			if !quiet {
 				log.Info("read sequences ...")
 			}
-			allRecords, err := fasta.GetSeqs(file, alphabet, chunkSize, threads, idRegexp)
+			allRecords, err := getAllRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
 			checkError(err)
 			if !quiet {
 				log.Infof("read %d sequences", len(allRecords))
 			}
 
-			recordsByID := make(map[string][]*fasta.FastaRecord)
+			recordsByID := make(map[string][]*seqRecord)
 
 			var id string
 			for _, record := range allRecords {
 				id = string(record.ID)
-				if _, ok := recordsByID[id]; !ok {
-					recordsByID[id] = []*fasta.FastaRecord{}
-				}
 				recordsByID[id] = append(recordsByID[id], record)
 			}
 
 			var outfile string
 			for id, records := range recordsByID {
 				outfile = fmt.Sprintf("%s.id_%s%s", fileName, id, fileExt)
-				writeSeqs(records, outfile, lineWidth, quiet, dryRun)
+				mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
 			}
 			return
 		}
@@ -290,13 +428,13 @@ Examples:
 			if !quiet {
 				log.Info("read sequences ...")
 			}
-			allRecords, err := fasta.GetSeqs(file, alphabet, chunkSize, threads, idRegexp)
+			allRecords, err := getAllRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
 			checkError(err)
 			if !quiet {
 				log.Infof("read %d sequences", len(allRecords))
 			}
 
-			recordsBySeqs := make(map[string][]*fasta.FastaRecord)
+			recordsBySeqs := make(map[string][]*seqRecord)
 
 			var subseq string
 			var s, e int
@@ -310,12 +448,9 @@ Examples:
 				}
 
 				if usingMD5 {
-					subseq = string(MD5(byteutil.SubSlice(record.Seq.Seq, s, e)))
+					subseq = string(MD5(byteutil.SubSlice(record.Seq, s, e)))
 				} else {
-					subseq = string(byteutil.SubSlice(record.Seq.Seq, s, e))
-				}
-				if _, ok := recordsBySeqs[subseq]; !ok {
-					recordsBySeqs[subseq] = []*fasta.FastaRecord{}
+					subseq = string(byteutil.SubSlice(record.Seq, s, e))
 				}
 				recordsBySeqs[subseq] = append(recordsBySeqs[subseq], record)
 			}
@@ -323,7 +458,7 @@ Examples:
 			var outfile string
 			for subseq, records := range recordsBySeqs {
 				outfile = fmt.Sprintf("%s.region_%d:%d_%s%s", fileName, start, end, subseq, fileExt)
-				writeSeqs(records, outfile, lineWidth, quiet, dryRun)
+				mc.writeSeqs(records, outfile, lineWidth, quiet, isFastq)
 			}
 			return
 		}
@@ -343,4 +478,206 @@ func init() {
 	splitCmd.Flags().BoolP("md5", "m", false, "use MD5 instead of region sequence in output file when using flag -r (--by-region)")
 	splitCmd.Flags().BoolP("two-pass", "2", false, "when sample by number 2-pass mode, low memory usage")
 	splitCmd.Flags().BoolP("dry-run", "d", false, "dry run, just print message and no files will be created.")
+	splitCmd.Flags().Bool("by-mean-qual", false, "(FASTQ only) split into files by rounded mean Phred quality, e.g. part_Q20.fastq")
+	splitCmd.Flags().Float64("min-qual", 0, "(FASTQ only) route reads with mean quality below N into a separate .lowqual file, split the rest normally")
+	splitCmd.Flags().String("part-strategy", "sequential", "strategy for -p/--by-part: sequential (contiguous parts, single-pass zero-copy on seekable files; "+
+		"falls back to roundrobin for stdin/compressed input or with --out-compress/--out-dir/--manifest), "+
+		"roundrobin (bounded memory, dispatch record i to part i%N), balanced-bases (bounded memory, dispatch each record to the currently-smallest part by total bases)")
+	splitCmd.Flags().String("by-bases", "", "split sequences into multi parts with up to N bases each, e.g. 10M, 500k, 1G")
+	splitCmd.Flags().Bool("no-split-seq", true, "(with --by-bases) do not split a single sequence across parts; "+
+		"set to false to cut long sequences into _chunk_NNN pieces tagged with /offset=START-END so parts stay balanced by length")
+	splitCmd.Flags().Bool("by-frame", false, "split by translated reading frame, into up to 6 files: <base>.frame_+1.faa ... <base>.frame_-3.faa")
+	splitCmd.Flags().Int("trans-table", 1, "NCBI genetic code table to use with --by-frame (1, 2, 3, 4, 5 or 11)")
+	splitCmd.Flags().Int("min-orf", 0, "(with --by-frame) discard translated fragments between stop codons shorter than N amino acids")
+	splitCmd.Flags().Bool("longest-orf-only", false, "(with --by-frame) keep only the single longest ORF per frame per record")
+	splitCmd.Flags().String("out-compress", "none", "stream each output part through a compressor: none, gz, bgzf (tabix/samtools-indexable) or zst")
+	splitCmd.Flags().String("out-dir", "", "write parts into this directory instead of alongside the input (created if missing)")
+	splitCmd.Flags().String("manifest", "", "write a TSV sidecar (filename, num_seqs, total_bases, min_len, max_len, sha256), one row per produced part; "+
+		"printed to stdout instead under --dry-run")
+}
+
+// stdinToTempFile drains os.Stdin into a temp file and returns its path.
+// Several split modes (format sniffing, byte-offset scanning, two-pass
+// counting) need to read the input more than once, which a stdin pipe
+// can't support directly.
+func stdinToTempFile() (string, error) {
+	tmp, err := os.CreateTemp("", "seqkit-split-stdin-*")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, os.Stdin); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// detectIsFastq sniffs the first non-empty byte of file ('-' for stdin) and
+// reports whether the input looks like FASTQ ('@') rather than FASTA ('>').
+func detectIsFastq(file string) (bool, error) {
+	fh, err := xopen.Ropen(file)
+	if err != nil {
+		return false, err
+	}
+	defer fh.Close()
+
+	buf := make([]byte, 1)
+	for {
+		n, err := fh.Read(buf)
+		if n > 0 {
+			switch buf[0] {
+			case '@':
+				return true, nil
+			case '>':
+				return false, nil
+			case '\r', '\n', ' ', '\t':
+				continue
+			default:
+				return false, nil
+			}
+		}
+		if err != nil {
+			return false, nil
+		}
+	}
+}
+
+// fastqExt returns the output extension (.fastq/.fq/.fastq.gz) matching the
+// input file's own FASTQ naming, so split parts look like the source.
+func fastqExt(file string) string {
+	lower := strings.ToLower(file)
+	switch {
+	case strings.HasSuffix(lower, ".fq.gz"):
+		return ".fq.gz"
+	case strings.HasSuffix(lower, ".fastq.gz"):
+		return ".fastq.gz"
+	case strings.HasSuffix(lower, ".fq"):
+		return ".fq"
+	default:
+		return ".fastq"
+	}
+}
+
+// meanQual returns the unrounded mean Phred quality of a FASTQ record, decoding
+// each byte of the Phred+33 encoded qual string. It returns 0 for FASTA
+// records (nil Qual).
+func meanQual(qual []byte) float64 {
+	if len(qual) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, q := range qual {
+		sum += int(q) - 33 // Phred+33
+	}
+	return float64(sum) / float64(len(qual))
+}
+
+// streamRecords reads file chunk-by-chunk (FASTA or FASTQ) and emits
+// individual seqRecords on a channel, closing it when done. The error
+// channel receives exactly one value (nil on success), sent just before the
+// record channel is closed.
+func streamRecords(file string, alphabet *seq.Alphabet, chunkSize, threads int, idRegexp string, isFastq bool) (<-chan *seqRecord, <-chan error) {
+	out := make(chan *seqRecord, 128)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		if isFastq {
+			fastqReader, err := fastq.NewFastqReader(file, chunkSize, threads, idRegexp)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for chunk := range fastqReader.Ch {
+				if chunk.Err != nil {
+					errCh <- chunk.Err
+					return
+				}
+				for _, record := range chunk.Data {
+					out <- &seqRecord{ID: record.ID, Seq: record.Seq.Seq, Qual: record.Qual}
+				}
+			}
+		} else {
+			fastaReader, err := fasta.NewFastaReader(alphabet, file, chunkSize, threads, idRegexp)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			for chunk := range fastaReader.Ch {
+				if chunk.Err != nil {
+					errCh <- chunk.Err
+					return
+				}
+				for _, record := range chunk.Data {
+					out <- &seqRecord{ID: record.ID, Seq: record.Seq.Seq}
+				}
+			}
+		}
+		errCh <- nil
+	}()
+
+	return out, errCh
+}
+
+// getAllRecords reads the whole file into memory as seqRecords, regardless
+// of whether it is FASTA or FASTQ.
+func getAllRecords(file string, alphabet *seq.Alphabet, chunkSize, threads int, idRegexp string, isFastq bool) ([]*seqRecord, error) {
+	records := []*seqRecord{}
+	recordCh, errCh := streamRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+	for record := range recordCh {
+		records = append(records, record)
+	}
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// countRecords counts the number of records in file without keeping them in
+// memory, for two-pass splitting.
+func countRecords(file string, alphabet *seq.Alphabet, isFastq bool) (int, error) {
+	n := 0
+	recordCh, errCh := streamRecords(file, alphabet, 5000, 1, "", isFastq)
+	for range recordCh {
+		n++
+	}
+	return n, <-errCh
+}
+
+// splitBySizeOrPart writes records into N parts, computing a per-part size
+// from part (number of parts) when size is not already given.
+func splitBySizeOrPart(mc *manifestCollector, records []*seqRecord, fileName, fileExt string, size, part int, lineWidth int, quiet, isFastq bool) {
+	n := len(records)
+	if size <= 0 {
+		if part > 0 {
+			if n%part > 0 {
+				size = int(n/part) + 1
+			} else {
+				size = int(n / part)
+			}
+		} else {
+			size = n // neither -s nor -p given: write everything as one part
+		}
+	}
+	if size <= 0 {
+		size = n
+	}
+
+	i := 1
+	buf := []*seqRecord{}
+	for _, record := range records {
+		buf = append(buf, record)
+		if len(buf) == size {
+			outfile := fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
+			mc.writeSeqs(buf, outfile, lineWidth, quiet, isFastq)
+			i++
+			buf = []*seqRecord{}
+		}
+	}
+	if len(buf) > 0 {
+		outfile := fmt.Sprintf("%s.part_%03d%s", fileName, i, fileExt)
+		mc.writeSeqs(buf, outfile, lineWidth, quiet, isFastq)
+	}
 }
@@ -0,0 +1,125 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseBaseSize parses sizes like "10M", "500k", "1G" (decimal k/M/G, case
+// insensitive) or a bare number of bases, as used by --by-bases.
+func parseBaseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+	mult := int64(1)
+	suffix := s[len(s)-1]
+	switch suffix {
+	case 'k', 'K':
+		mult = 1e3
+		s = s[:len(s)-1]
+	case 'm', 'M':
+		mult = 1e6
+		s = s[:len(s)-1]
+	case 'g', 'G':
+		mult = 1e9
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// splitByBases accumulates records into parts until each part's summed
+// sequence length reaches sizeBases, then rolls over to the next part. When
+// noSplitSeq is false, a single record longer than sizeBases is itself cut
+// into _chunk_NNN pieces (tagged with /offset=START-END) so it can span
+// multiple parts; when true (the default), such a record is kept whole in
+// its own part.
+func splitByBases(mc *manifestCollector, records []*seqRecord, fileName, fileExt string, sizeBases int64, noSplitSeq bool, lineWidth int, quiet, isFastq bool) {
+	partNum := 1
+	buf := []*seqRecord{}
+	var bufBases int64
+
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		outfile := fmt.Sprintf("%s.part_%03d%s", fileName, partNum, fileExt)
+		mc.writeSeqs(buf, outfile, lineWidth, quiet, isFastq)
+		buf = []*seqRecord{}
+		bufBases = 0
+		partNum++
+	}
+
+	for _, record := range records {
+		recLen := int64(len(record.Seq))
+
+		if noSplitSeq || recLen <= sizeBases {
+			if bufBases > 0 && bufBases+recLen > sizeBases {
+				flush()
+			}
+			buf = append(buf, record)
+			bufBases += recLen
+			if bufBases >= sizeBases {
+				flush()
+			}
+			continue
+		}
+
+		// record itself is longer than a part: cut it into chunks,
+		// filling whatever room is left in the current part first.
+		chunkIdx := 1
+		pos := 0
+		n := len(record.Seq)
+		for pos < n {
+			room := sizeBases - bufBases
+			if room <= 0 {
+				flush()
+				room = sizeBases
+			}
+			end := pos + int(room)
+			if end > n {
+				end = n
+			}
+
+			id := fmt.Sprintf("%s_chunk_%03d /offset=%d-%d", record.ID, chunkIdx, pos+1, end)
+			chunk := &seqRecord{ID: []byte(id), Seq: record.Seq[pos:end]}
+			if isFastq {
+				chunk.Qual = record.Qual[pos:end]
+			}
+			buf = append(buf, chunk)
+			bufBases += int64(end - pos)
+
+			pos = end
+			chunkIdx++
+			if bufBases >= sizeBases {
+				flush()
+			}
+		}
+	}
+
+	flush()
+}
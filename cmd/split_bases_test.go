@@ -0,0 +1,98 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import "testing"
+
+func TestParseBaseSize(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"10M", 10e6, false},
+		{"500k", 500e3, false},
+		{"1G", 1e9, false},
+		{"1g", 1e9, false},
+		{"42", 42, false},
+		{"1.5M", int64(1.5e6), false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"k", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseBaseSize(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseBaseSize(%q) expected error, got %d", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBaseSize(%q) unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseBaseSize(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSplitByBasesRollsOverParts(t *testing.T) {
+	records := []*seqRecord{
+		{ID: []byte("r1"), Seq: []byte("ACGTACGTAC")}, // 10 bases
+		{ID: []byte("r2"), Seq: []byte("ACGTACGTAC")}, // 10 bases
+		{ID: []byte("r3"), Seq: []byte("ACGT")},       // 4 bases
+	}
+
+	mc := newManifestCollector("", "none", true) // dry-run: no files touched
+	splitByBases(mc, records, "out", ".fasta", 15, true, 60, true, false)
+
+	if len(mc.rows) != 2 {
+		t.Fatalf("got %d parts, want 2", len(mc.rows))
+	}
+	if mc.rows[0].NumSeqs != 1 || mc.rows[0].TotalBases != 10 {
+		t.Errorf("part 1 = %+v, want 1 seq / 10 bases", mc.rows[0])
+	}
+	if mc.rows[1].NumSeqs != 2 || mc.rows[1].TotalBases != 14 {
+		t.Errorf("part 2 = %+v, want 2 seqs / 14 bases", mc.rows[1])
+	}
+}
+
+func TestSplitByBasesCutsOversizedRecord(t *testing.T) {
+	records := []*seqRecord{
+		{ID: []byte("r1"), Seq: []byte("ACGTACGTACGTACGTACGT")}, // 20 bases
+	}
+
+	mc := newManifestCollector("", "none", true)
+	splitByBases(mc, records, "out", ".fasta", 8, false, 60, true, false)
+
+	if len(mc.rows) != 3 {
+		t.Fatalf("got %d parts, want 3 (8+8+4 bases)", len(mc.rows))
+	}
+	var total int64
+	for _, row := range mc.rows {
+		total += row.TotalBases
+	}
+	if total != 20 {
+		t.Errorf("total bases across parts = %d, want 20", total)
+	}
+}
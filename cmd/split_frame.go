@@ -0,0 +1,211 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+)
+
+// frameLabels lists the six reading frames in the order they are emitted.
+var frameLabels = []string{"+1", "+2", "+3", "-1", "-2", "-3"}
+
+// standardCodonTable is NCBI genetic code table 1, keyed by uppercase DNA
+// codon. It is the base table that the other supported tables are derived
+// from by applying a small diff.
+var standardCodonTable = map[string]byte{
+	"TTT": 'F', "TTC": 'F', "TTA": 'L', "TTG": 'L',
+	"CTT": 'L', "CTC": 'L', "CTA": 'L', "CTG": 'L',
+	"ATT": 'I', "ATC": 'I', "ATA": 'I', "ATG": 'M',
+	"GTT": 'V', "GTC": 'V', "GTA": 'V', "GTG": 'V',
+	"TCT": 'S', "TCC": 'S', "TCA": 'S', "TCG": 'S',
+	"CCT": 'P', "CCC": 'P', "CCA": 'P', "CCG": 'P',
+	"ACT": 'T', "ACC": 'T', "ACA": 'T', "ACG": 'T',
+	"GCT": 'A', "GCC": 'A', "GCA": 'A', "GCG": 'A',
+	"TAT": 'Y', "TAC": 'Y', "TAA": '*', "TAG": '*',
+	"CAT": 'H', "CAC": 'H', "CAA": 'Q', "CAG": 'Q',
+	"AAT": 'N', "AAC": 'N', "AAA": 'K', "AAG": 'K',
+	"GAT": 'D', "GAC": 'D', "GAA": 'E', "GAG": 'E',
+	"TGT": 'C', "TGC": 'C', "TGA": '*', "TGG": 'W',
+	"CGT": 'R', "CGC": 'R', "CGA": 'R', "CGG": 'R',
+	"AGT": 'S', "AGC": 'S', "AGA": 'R', "AGG": 'R',
+	"GGT": 'G', "GGC": 'G', "GGA": 'G', "GGG": 'G',
+}
+
+// codonTableDiffs holds, per supported NCBI translation table, only the
+// codons whose amino acid differs from standardCodonTable.
+var codonTableDiffs = map[int]map[string]byte{
+	1:  {},
+	2:  {"AGA": '*', "AGG": '*', "ATA": 'M', "TGA": 'W'},                         // vertebrate mitochondrial
+	3:  {"ATA": 'M', "CTT": 'T', "CTC": 'T', "CTA": 'T', "CTG": 'T', "TGA": 'W'}, // yeast mitochondrial
+	4:  {"TGA": 'W'},                                                             // mold/protozoan/coelenterate mitochondrial; mycoplasma/spiroplasma
+	5:  {"AGA": 'S', "AGG": 'S', "ATA": 'M', "TGA": 'W'},                         // invertebrate mitochondrial
+	11: {},                                                                       // bacterial/archaeal/plant plastid; same aa assignments as the standard table
+}
+
+// geneticCodeTable builds the codon->amino acid map for NCBI translation
+// table n, by layering codonTableDiffs[n] over standardCodonTable.
+func geneticCodeTable(n int) (map[string]byte, error) {
+	diff, ok := codonTableDiffs[n]
+	if !ok {
+		return nil, fmt.Errorf("unsupported --trans-table: %d (supported: 1, 2, 3, 4, 5, 11)", n)
+	}
+	table := make(map[string]byte, len(standardCodonTable))
+	for codon, aa := range standardCodonTable {
+		table[codon] = aa
+	}
+	for codon, aa := range diff {
+		table[codon] = aa
+	}
+	return table, nil
+}
+
+var complementBase = map[byte]byte{
+	'A': 'T', 'T': 'A', 'C': 'G', 'G': 'C', 'U': 'A',
+	'a': 't', 't': 'a', 'c': 'g', 'g': 'c', 'u': 'a',
+	'N': 'N', 'n': 'n',
+}
+
+// reverseComplement returns the reverse complement of a nucleotide sequence,
+// leaving any base outside the standard IUPAC-ish set above (gap characters,
+// ambiguity codes other than N) unchanged rather than guessing. translateFrame
+// already emits 'X' for any codon it can't look up, so this doesn't need to
+// normalize such bytes itself.
+func reverseComplement(s []byte) []byte {
+	n := len(s)
+	rc := make([]byte, n)
+	for i, b := range s {
+		c, ok := complementBase[b]
+		if !ok {
+			c = b
+		}
+		rc[n-1-i] = c
+	}
+	return rc
+}
+
+// translateFrame translates s in one of the three codon offsets (0, 1 or 2),
+// emitting '*' for stop codons and 'X' for codons containing an ambiguous
+// base or otherwise not found in table. Trailing bases that don't form a
+// full codon are dropped.
+func translateFrame(s []byte, offset int, table map[string]byte) []byte {
+	aa := []byte{}
+	for i := offset; i+3 <= len(s); i += 3 {
+		codon := upperCodon(s[i : i+3])
+		if a, ok := table[codon]; ok {
+			aa = append(aa, a)
+		} else {
+			aa = append(aa, 'X')
+		}
+	}
+	return aa
+}
+
+func upperCodon(codon []byte) string {
+	buf := make([]byte, 3)
+	for i, b := range codon {
+		if b >= 'a' && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		buf[i] = b
+	}
+	return string(buf)
+}
+
+// orfsFromTranslation splits a translated frame on stop codons ('*') into
+// the fragments between them, dropping the stops themselves, and keeps only
+// fragments at least minOrf amino acids long. When longestOnly is set, only
+// the single longest surviving fragment is kept.
+func orfsFromTranslation(aa []byte, minOrf int, longestOnly bool) [][]byte {
+	fragments := [][]byte{}
+	start := 0
+	for i := 0; i <= len(aa); i++ {
+		if i == len(aa) || aa[i] == '*' {
+			if i > start {
+				frag := aa[start:i]
+				if len(frag) >= minOrf {
+					fragments = append(fragments, frag)
+				}
+			}
+			start = i + 1
+		}
+	}
+
+	if longestOnly && len(fragments) > 1 {
+		longest := fragments[0]
+		for _, f := range fragments[1:] {
+			if len(f) > len(longest) {
+				longest = f
+			}
+		}
+		return [][]byte{longest}
+	}
+	return fragments
+}
+
+// splitByFrame translates each input nucleotide record in all six reading
+// frames and writes the results into up to six protein FASTA files,
+// <fileName>.frame_<label>.faa.
+func splitByFrame(mc *manifestCollector, records []*seqRecord, fileName string, transTable, minOrf int, longestOrfOnly bool, lineWidth int, quiet bool) error {
+	table, err := geneticCodeTable(transTable)
+	if err != nil {
+		return err
+	}
+
+	byFrame := make(map[string][]*seqRecord, len(frameLabels))
+
+	for _, record := range records {
+		rc := reverseComplement(record.Seq)
+
+		for i, label := range frameLabels {
+			var translated []byte
+			if i < 3 {
+				translated = translateFrame(record.Seq, i, table)
+			} else {
+				translated = translateFrame(rc, i-3, table)
+			}
+
+			var orfs [][]byte
+			if minOrf > 0 || longestOrfOnly {
+				orfs = orfsFromTranslation(translated, minOrf, longestOrfOnly)
+			} else {
+				orfs = [][]byte{translated}
+			}
+
+			for idx, orf := range orfs {
+				id := fmt.Sprintf("%s_frame=%s", record.ID, label)
+				if len(orfs) > 1 {
+					id = fmt.Sprintf("%s_orf%02d", id, idx+1)
+				}
+				byFrame[label] = append(byFrame[label], &seqRecord{ID: []byte(id), Seq: orf})
+			}
+		}
+	}
+
+	for _, label := range frameLabels {
+		recs := byFrame[label]
+		if len(recs) == 0 {
+			continue
+		}
+		outfile := fmt.Sprintf("%s.frame_%s.faa", fileName, label)
+		mc.writeProteinSeqs(recs, outfile, lineWidth, quiet)
+	}
+	return nil
+}
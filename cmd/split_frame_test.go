@@ -0,0 +1,127 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGeneticCodeTable(t *testing.T) {
+	std, err := geneticCodeTable(1)
+	if err != nil {
+		t.Fatalf("geneticCodeTable(1): %v", err)
+	}
+	if std["AGA"] != 'R' || std["TGA"] != '*' || std["ATA"] != 'I' {
+		t.Errorf("table 1 codon assignments unexpected: AGA=%c TGA=%c ATA=%c", std["AGA"], std["TGA"], std["ATA"])
+	}
+
+	vertMito, err := geneticCodeTable(2)
+	if err != nil {
+		t.Fatalf("geneticCodeTable(2): %v", err)
+	}
+	if vertMito["AGA"] != '*' || vertMito["ATA"] != 'M' || vertMito["TGA"] != 'W' {
+		t.Errorf("table 2 diffs not applied: AGA=%c ATA=%c TGA=%c", vertMito["AGA"], vertMito["ATA"], vertMito["TGA"])
+	}
+	// codons not in the diff must still match the standard table
+	if vertMito["GGT"] != std["GGT"] {
+		t.Errorf("table 2 should inherit GGT=%c from the standard table, got %c", std["GGT"], vertMito["GGT"])
+	}
+
+	if _, err := geneticCodeTable(99); err == nil {
+		t.Error("geneticCodeTable(99) should error on an unsupported table")
+	}
+}
+
+func TestTranslateFrame(t *testing.T) {
+	table, err := geneticCodeTable(1)
+	if err != nil {
+		t.Fatalf("geneticCodeTable(1): %v", err)
+	}
+
+	got := translateFrame([]byte("ATGAAATAG"), 0, table)
+	if !bytes.Equal(got, []byte("MK*")) {
+		t.Errorf("translateFrame offset 0 = %q, want %q", got, "MK*")
+	}
+
+	// offset 1 drops the leading base and any trailing partial codon
+	got = translateFrame([]byte("ATGAAATAG"), 1, table)
+	if !bytes.Equal(got, []byte("*N")) {
+		t.Errorf("translateFrame offset 1 = %q, want %q", got, "*N")
+	}
+
+	// unrecognized codon (ambiguity code) translates to 'X'
+	got = translateFrame([]byte("NNNATG"), 0, table)
+	if !bytes.Equal(got, []byte("XM")) {
+		t.Errorf("translateFrame with ambiguous codon = %q, want %q", got, "XM")
+	}
+
+	// lowercase input is translated like uppercase
+	got = translateFrame([]byte("atgaaatag"), 0, table)
+	if !bytes.Equal(got, []byte("MK*")) {
+		t.Errorf("translateFrame lowercase = %q, want %q", got, "MK*")
+	}
+}
+
+func TestOrfsFromTranslation(t *testing.T) {
+	aa := []byte("MK*QYY*RR")
+	orfs := orfsFromTranslation(aa, 0, false)
+	if len(orfs) != 3 || string(orfs[0]) != "MK" || string(orfs[1]) != "QYY" || string(orfs[2]) != "RR" {
+		t.Errorf("orfsFromTranslation(minOrf=0) = %q, want [\"MK\" \"QYY\" \"RR\"] (trailing fragment with no terminating stop is still kept)", orfsToStrings(orfs))
+	}
+
+	// minOrf filters out fragments shorter than the threshold
+	orfs = orfsFromTranslation(aa, 3, false)
+	if len(orfs) != 1 || string(orfs[0]) != "QYY" {
+		t.Errorf("orfsFromTranslation(minOrf=3) = %q, want [\"QYY\"]", orfsToStrings(orfs))
+	}
+
+	// longestOnly keeps a single fragment, the longest one
+	orfs = orfsFromTranslation(aa, 0, true)
+	if len(orfs) != 1 || string(orfs[0]) != "QYY" {
+		t.Errorf("orfsFromTranslation(longestOnly) = %q, want [\"QYY\"]", orfsToStrings(orfs))
+	}
+}
+
+func orfsToStrings(orfs [][]byte) []string {
+	out := make([]string, len(orfs))
+	for i, o := range orfs {
+		out[i] = string(o)
+	}
+	return out
+}
+
+func TestReverseComplement(t *testing.T) {
+	// ACGT is its own reverse complement
+	if got := reverseComplement([]byte("ACGT")); !bytes.Equal(got, []byte("ACGT")) {
+		t.Errorf("reverseComplement(ACGT) = %q, want %q", got, "ACGT")
+	}
+
+	// a gap character outside the mapped set passes through unchanged,
+	// matching the function's doc comment
+	if got := reverseComplement([]byte("ACGT-")); !bytes.Equal(got, []byte("-ACGT")) {
+		t.Errorf("reverseComplement(ACGT-) = %q, want %q", got, "-ACGT")
+	}
+
+	if got := reverseComplement([]byte("")); len(got) != 0 {
+		t.Errorf("reverseComplement(\"\") = %q, want empty", got)
+	}
+}
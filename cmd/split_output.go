@@ -0,0 +1,270 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/biogo/hts/bgzf"
+	"github.com/klauspost/compress/zstd"
+	"github.com/shenwei356/util/byteutil"
+)
+
+// ManifestRow is one row of the --manifest TSV sidecar, one per produced
+// output part.
+type ManifestRow struct {
+	Filename   string
+	NumSeqs    int
+	TotalBases int64
+	MinLen     int
+	MaxLen     int
+	SHA256     string
+}
+
+// manifestCollector is the single choke point every split mode writes parts
+// through, except the zero-copy splitByPartOffsets path, which writes raw
+// byte ranges itself and is only used when no --out-compress, --out-dir or
+// --manifest is given. It applies --out-dir placement and --out-compress, and (whether
+// or not a file actually hits disk, i.e. even under --dry-run) accumulates
+// the per-part stats needed for --manifest.
+type manifestCollector struct {
+	outDir   string
+	compress string
+	dryRun   bool
+	rows     []ManifestRow
+}
+
+func newManifestCollector(outDir, compress string, dryRun bool) *manifestCollector {
+	return &manifestCollector{outDir: outDir, compress: compress, dryRun: dryRun}
+}
+
+// resolvedName returns the on-disk name for a logical output filename,
+// applying --out-dir and the --out-compress suffix.
+func (mc *manifestCollector) resolvedName(name string) string {
+	switch mc.compress {
+	case "gz":
+		name += ".gz"
+	case "bgzf":
+		name += ".bgzf"
+	case "zst":
+		name += ".zst"
+	}
+	if mc.outDir != "" {
+		return filepath.Join(mc.outDir, name)
+	}
+	return name
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// open returns a writer for logicalName (which it runs through resolvedName),
+// transparently applying the selected compressor, and a sha256 hash that is
+// fed the bytes actually landing on disk (i.e. tapped after compression, not
+// before), so --manifest's checksum matches the real file. Under --dry-run
+// nothing is written to disk, but the hash still reflects what would have
+// been written.
+func (mc *manifestCollector) open(logicalName string) (io.WriteCloser, hash.Hash, string, error) {
+	path := mc.resolvedName(logicalName)
+	hasher := sha256.New()
+
+	var sink io.Writer = hasher
+	var underlying io.Closer = nopWriteCloser{io.Discard}
+
+	if !mc.dryRun {
+		if mc.outDir != "" {
+			if err := os.MkdirAll(mc.outDir, 0755); err != nil {
+				return nil, nil, path, err
+			}
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, nil, path, err
+		}
+		sink = io.MultiWriter(f, hasher)
+		underlying = f
+	}
+
+	switch mc.compress {
+	case "gz":
+		return writeCloserWithUnderlying{gzip.NewWriter(sink), underlying}, hasher, path, nil
+	case "bgzf":
+		return writeCloserWithUnderlying{bgzf.NewWriter(sink, 1), underlying}, hasher, path, nil
+	case "zst":
+		zw, err := zstd.NewWriter(sink)
+		if err != nil {
+			underlying.Close()
+			return nil, nil, path, err
+		}
+		return writeCloserWithUnderlying{zw, underlying}, hasher, path, nil
+	default:
+		return writeCloserWithUnderlying{nopWriteCloser{sink}, underlying}, hasher, path, nil
+	}
+}
+
+// writeCloserWithUnderlying closes a compressor and then the raw sink it
+// wraps, in that order.
+type writeCloserWithUnderlying struct {
+	io.WriteCloser
+	underlying io.Closer
+}
+
+func (w writeCloserWithUnderlying) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		w.underlying.Close()
+		return err
+	}
+	return w.underlying.Close()
+}
+
+// partWriter accumulates manifest stats for one output part as records are
+// written to it through a sha256-hashing tee.
+type partWriter struct {
+	w        io.WriteCloser
+	dest     io.Writer
+	hasher   hash.Hash
+	filename string
+
+	numSeqs    int
+	totalBases int64
+	minLen     int
+	maxLen     int
+}
+
+// newPart opens a new output part named logicalName (e.g.
+// "seqs.part_001.fasta") and returns a writer for it.
+func (mc *manifestCollector) newPart(logicalName string) (*partWriter, error) {
+	w, hasher, path, err := mc.open(logicalName)
+	if err != nil {
+		return nil, err
+	}
+	return &partWriter{w: w, dest: w, hasher: hasher, filename: path, minLen: -1}, nil
+}
+
+func (pw *partWriter) writeFasta(record *seqRecord, lineWidth int) {
+	fmt.Fprintf(pw.dest, ">%s\n", record.ID)
+	pw.dest.Write(byteutil.WrapByteSlice(record.Seq, lineWidth))
+	pw.dest.Write([]byte{'\n'})
+	pw.account(len(record.Seq))
+}
+
+func (pw *partWriter) writeFastq(record *seqRecord, lineWidth int) {
+	fmt.Fprintf(pw.dest, "@%s\n", record.ID)
+	pw.dest.Write(byteutil.WrapByteSlice(record.Seq, lineWidth))
+	pw.dest.Write([]byte("\n+\n"))
+	pw.dest.Write(byteutil.WrapByteSlice(record.Qual, lineWidth))
+	pw.dest.Write([]byte{'\n'})
+	pw.account(len(record.Seq))
+}
+
+func (pw *partWriter) account(n int) {
+	pw.numSeqs++
+	pw.totalBases += int64(n)
+	if pw.minLen < 0 || n < pw.minLen {
+		pw.minLen = n
+	}
+	if n > pw.maxLen {
+		pw.maxLen = n
+	}
+}
+
+// close finalizes the part, returning its manifest row.
+func (pw *partWriter) close() (ManifestRow, error) {
+	if err := pw.w.Close(); err != nil {
+		return ManifestRow{}, err
+	}
+	minLen := pw.minLen
+	if minLen < 0 {
+		minLen = 0
+	}
+	return ManifestRow{
+		Filename:   pw.filename,
+		NumSeqs:    pw.numSeqs,
+		TotalBases: pw.totalBases,
+		MinLen:     minLen,
+		MaxLen:     pw.maxLen,
+		SHA256:     hex.EncodeToString(pw.hasher.Sum(nil)),
+	}, nil
+}
+
+// writeSeqs writes records (FASTA or FASTQ, per isFastq) as one output
+// part, routing through --out-dir/--out-compress and recording a manifest
+// row for it.
+func (mc *manifestCollector) writeSeqs(records []*seqRecord, logicalName string, lineWidth int, quiet, isFastq bool) {
+	if !quiet {
+		log.Infof("write %d sequences to file: %s", len(records), mc.resolvedName(logicalName))
+	}
+
+	pw, err := mc.newPart(logicalName)
+	checkError(err)
+
+	for _, record := range records {
+		if isFastq {
+			pw.writeFastq(record, lineWidth)
+		} else {
+			pw.writeFasta(record, lineWidth)
+		}
+	}
+
+	row, err := pw.close()
+	checkError(err)
+	mc.rows = append(mc.rows, row)
+}
+
+// writeProteinSeqs is the --by-frame analogue of writeSeqs: always FASTA,
+// regardless of the source format.
+func (mc *manifestCollector) writeProteinSeqs(records []*seqRecord, logicalName string, lineWidth int, quiet bool) {
+	mc.writeSeqs(records, logicalName, lineWidth, quiet, false)
+}
+
+// writeManifest emits the TSV sidecar: to manifestFile normally, or to
+// stdout when dryRun so pipelines can plan ahead without touching disk.
+func (mc *manifestCollector) writeManifest(manifestFile string) error {
+	if manifestFile == "" {
+		return nil
+	}
+
+	var out io.Writer
+	if mc.dryRun {
+		out = os.Stdout
+	} else {
+		fh, err := os.Create(manifestFile)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		out = fh
+	}
+
+	fmt.Fprintln(out, "filename\tnum_seqs\ttotal_bases\tmin_len\tmax_len\tsha256")
+	for _, row := range mc.rows {
+		fmt.Fprintf(out, "%s\t%d\t%d\t%d\t%d\t%s\n", row.Filename, row.NumSeqs, row.TotalBases, row.MinLen, row.MaxLen, row.SHA256)
+	}
+	return nil
+}
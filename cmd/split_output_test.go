@@ -0,0 +1,126 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assertManifestMatchesDisk re-decompresses (if needed) the file a row
+// points at and checks its sha256 against the manifest row, i.e. that the
+// hash reflects what's actually on disk rather than the pre-compression
+// stream.
+func assertManifestMatchesDisk(t *testing.T, row ManifestRow, compress string) {
+	t.Helper()
+
+	raw, err := os.ReadFile(row.Filename)
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", row.Filename, err)
+	}
+
+	sum := sha256.Sum256(raw)
+	got := hex.EncodeToString(sum[:])
+	if got != row.SHA256 {
+		t.Errorf("manifest sha256 %s does not match on-disk bytes of %s (got %s)", row.SHA256, row.Filename, got)
+	}
+
+	if compress == "gz" {
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("gzip.NewReader: %v", err)
+		}
+		defer zr.Close()
+		plain, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("reading gzip stream: %v", err)
+		}
+		if !bytes.Contains(plain, []byte(">r1")) {
+			t.Errorf("decompressed file does not contain the expected record: %q", plain)
+		}
+	}
+}
+
+func TestManifestCollectorGzipSHA256MatchesDisk(t *testing.T) {
+	outDir := t.TempDir()
+	mc := newManifestCollector(outDir, "gz", false)
+
+	records := []*seqRecord{
+		{ID: []byte("r1"), Seq: []byte("ACGTACGT")},
+	}
+	mc.writeSeqs(records, "out.part_001.fasta", 60, true, false)
+
+	if len(mc.rows) != 1 {
+		t.Fatalf("got %d manifest rows, want 1", len(mc.rows))
+	}
+	row := mc.rows[0]
+
+	wantPath := filepath.Join(outDir, "out.part_001.fasta.gz")
+	if row.Filename != wantPath {
+		t.Errorf("manifest filename = %q, want %q", row.Filename, wantPath)
+	}
+	if row.NumSeqs != 1 || row.TotalBases != 8 {
+		t.Errorf("manifest stats = %+v, want 1 seq / 8 bases", row)
+	}
+
+	assertManifestMatchesDisk(t, row, "gz")
+}
+
+func TestManifestCollectorNoCompressSHA256MatchesDisk(t *testing.T) {
+	outDir := t.TempDir()
+	mc := newManifestCollector(outDir, "none", false)
+
+	records := []*seqRecord{
+		{ID: []byte("r1"), Seq: []byte("ACGT")},
+	}
+	mc.writeSeqs(records, "out.part_001.fasta", 60, true, false)
+
+	row := mc.rows[0]
+	assertManifestMatchesDisk(t, row, "none")
+}
+
+func TestWriteManifestTSV(t *testing.T) {
+	mc := newManifestCollector("", "none", false)
+	mc.rows = []ManifestRow{
+		{Filename: "a.fasta", NumSeqs: 2, TotalBases: 10, MinLen: 3, MaxLen: 7, SHA256: "deadbeef"},
+	}
+
+	manifestFile := filepath.Join(t.TempDir(), "manifest.tsv")
+	if err := mc.writeManifest(manifestFile); err != nil {
+		t.Fatalf("writeManifest: %v", err)
+	}
+
+	content, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "filename\tnum_seqs\ttotal_bases\tmin_len\tmax_len\tsha256\n" +
+		"a.fasta\t2\t10\t3\t7\tdeadbeef\n"
+	if string(content) != want {
+		t.Errorf("manifest TSV = %q, want %q", content, want)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/brentp/xopen"
+	"github.com/shenwei356/bio/seq"
+)
+
+// recordSpan locates one record within the source file, in bytes, for the
+// zero-copy splitter. The request's (id, byte_offset, byte_length) shape
+// drops the id here: splitByPartOffsets only ever copies raw byte ranges
+// between contiguous part boundaries and never needs a record's id to do
+// that, so carrying it would mean parsing it out during the scan for no
+// consumer.
+type recordSpan struct {
+	Offset int64
+	Length int64
+}
+
+// compressedMagic holds the leading bytes of formats scanRecordSpans/
+// splitByPartOffsets must not be pointed at: they read raw bytes, so a
+// compressed stream (however it reached disk - by extension, or piped
+// through stdin into an extension-less temp file) would come out as
+// garbage rather than FASTA/FASTQ.
+var compressedMagic = [][]byte{
+	{0x1f, 0x8b},                     // gzip / bgzf
+	{'B', 'Z', 'h'},                  // bzip2
+	{0x28, 0xb5, 0x2f, 0xfd},         // zstd
+	{0xfd, '7', 'z', 'X', 'Z', 0x00}, // xz
+}
+
+// isSeekableFile reports whether file can be split by byte offset: it must
+// be a real file (not stdin) and not a compressed stream, since both the
+// offset scan and io.ReaderAt operate on raw bytes. Compression is detected
+// by extension (the common case) and, since stdin input is materialized to
+// an extension-less temp file before this is ever called, falls back to
+// sniffing the file's magic bytes.
+func isSeekableFile(file string) bool {
+	if file == "-" {
+		return false
+	}
+	lower := strings.ToLower(file)
+	for _, ext := range []string{".gz", ".bz2", ".zst", ".xz"} {
+		if strings.HasSuffix(lower, ext) {
+			return false
+		}
+	}
+
+	fh, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer fh.Close()
+
+	head := make([]byte, 6)
+	n, _ := io.ReadFull(fh, head)
+	head = head[:n]
+	for _, magic := range compressedMagic {
+		if len(head) >= len(magic) && string(head[:len(magic)]) == string(magic) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanRecordSpans makes a single lightweight pass over file recording only
+// the byte offset and length of each record, never materializing sequences.
+func scanRecordSpans(file string, isFastq bool) ([]recordSpan, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	spans := []recordSpan{}
+	reader := bufio.NewReaderSize(fh, 1<<20)
+
+	var pos int64
+	if isFastq {
+		var recStart int64
+		lineNo := 0
+		for {
+			line, err := reader.ReadBytes('\n')
+			n := int64(len(line))
+			if lineNo%4 == 0 {
+				recStart = pos
+			}
+			if lineNo%4 == 3 {
+				spans = append(spans, recordSpan{Offset: recStart, Length: pos + n - recStart})
+			}
+			pos += n
+			if err != nil {
+				break
+			}
+			lineNo++
+		}
+		return spans, nil
+	}
+
+	var recStart int64 = -1
+	for {
+		line, err := reader.ReadBytes('\n')
+		n := int64(len(line))
+		if len(line) > 0 && line[0] == '>' {
+			if recStart >= 0 {
+				spans = append(spans, recordSpan{Offset: recStart, Length: pos - recStart})
+			}
+			recStart = pos
+		}
+		pos += n
+		if err != nil {
+			break
+		}
+	}
+	if recStart >= 0 {
+		spans = append(spans, recordSpan{Offset: recStart, Length: pos - recStart})
+	}
+	return spans, nil
+}
+
+// splitByPartOffsets implements the single-pass, bounded-memory --by-part
+// splitter: it scans record spans once, computes N contiguous part
+// boundaries, then copies each part's raw byte range straight from the
+// source file to its output writer via io.ReaderAt, never building a
+// FastaRecord/FastqRecord for the bulk of the data.
+func splitByPartOffsets(file, fileName, fileExt string, part int, quiet, dryRun bool, isFastq bool) error {
+	spans, err := scanRecordSpans(file, isFastq)
+	if err != nil {
+		return err
+	}
+	n := len(spans)
+	if n == 0 {
+		return nil
+	}
+
+	size := n / part
+	if n%part > 0 {
+		size++
+	}
+	if !quiet {
+		log.Infof("%d records, %d per part", n, size)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, part)
+
+	for i, lo := 0, 0; lo < n; i, lo = i+1, lo+size {
+		hi := lo + size
+		if hi > n {
+			hi = n
+		}
+		start := spans[lo].Offset
+		end := spans[hi-1].Offset + spans[hi-1].Length
+
+		wg.Add(1)
+		go func(partNum int, start, end int64) {
+			defer wg.Done()
+			outfile := fmt.Sprintf("%s.part_%03d%s", fileName, partNum, fileExt)
+			if !quiet {
+				log.Infof("write part %d (%d bytes) to file: %s", partNum, end-start, outfile)
+			}
+			outfh, err := xopen.Wopen(outfile)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer outfh.Close()
+
+			_, err = io.Copy(outfh, io.NewSectionReader(src, start, end-start))
+			errs <- err
+		}(i+1, start, end)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitByPartDispatch implements the bounded-memory fallback for
+// non-seekable input (stdin, gzip): it opens all N output writers up front
+// and, as each record streams in, dispatches it to a writer according to
+// strategy, without ever buffering the whole input.
+func splitByPartDispatch(mc *manifestCollector, file string, alphabet *seq.Alphabet, chunkSize, threads int, idRegexp, fileName, fileExt string, part, lineWidth int, quiet, isFastq bool, strategy string) error {
+	parts := make([]*partWriter, part)
+	bases := make([]int64, part)
+	for i := 0; i < part; i++ {
+		outfile := fmt.Sprintf("%s.part_%03d%s", fileName, i+1, fileExt)
+		pw, err := mc.newPart(outfile)
+		if err != nil {
+			return err
+		}
+		parts[i] = pw
+		if !quiet {
+			log.Infof("opened part file: %s", mc.resolvedName(outfile))
+		}
+	}
+
+	recordCh, errCh := streamRecords(file, alphabet, chunkSize, threads, idRegexp, isFastq)
+
+	i := 0
+	for record := range recordCh {
+		var w int
+		if strategy == "balanced-bases" {
+			w = 0
+			for j := 1; j < part; j++ {
+				if bases[j] < bases[w] {
+					w = j
+				}
+			}
+		} else {
+			w = i % part
+		}
+		if isFastq {
+			parts[w].writeFastq(record, lineWidth)
+		} else {
+			parts[w].writeFasta(record, lineWidth)
+		}
+		bases[w] += int64(len(record.Seq))
+		i++
+	}
+
+	for _, pw := range parts {
+		row, err := pw.close()
+		if err != nil {
+			return err
+		}
+		mc.rows = append(mc.rows, row)
+	}
+
+	return <-errCh
+}
@@ -0,0 +1,126 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestScanRecordSpansFasta(t *testing.T) {
+	content := ">r1\nACGT\n>r2\nAC\nGT\n>r3\nA\n"
+	path := writeTempFile(t, "in.fasta", content)
+
+	spans, err := scanRecordSpans(path, false)
+	if err != nil {
+		t.Fatalf("scanRecordSpans: %v", err)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	for _, sp := range spans {
+		got := content[sp.Offset : sp.Offset+sp.Length]
+		if len(got) == 0 || got[0] != '>' {
+			t.Errorf("span %+v does not start a record: %q", sp, got)
+		}
+	}
+	// spans must be contiguous and cover the whole file
+	if spans[0].Offset != 0 {
+		t.Errorf("first span should start at offset 0, got %d", spans[0].Offset)
+	}
+	last := spans[len(spans)-1]
+	if last.Offset+last.Length != int64(len(content)) {
+		t.Errorf("last span should end at EOF (%d), got %d", len(content), last.Offset+last.Length)
+	}
+}
+
+func TestScanRecordSpansFastq(t *testing.T) {
+	content := "@r1\nACGT\n+\nIIII\n@r2\nAC\n+\nII\n"
+	path := writeTempFile(t, "in.fastq", content)
+
+	spans, err := scanRecordSpans(path, true)
+	if err != nil {
+		t.Fatalf("scanRecordSpans: %v", err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	want := []string{"@r1\nACGT\n+\nIIII\n", "@r2\nAC\n+\nII\n"}
+	for i, sp := range spans {
+		got := content[sp.Offset : sp.Offset+sp.Length]
+		if got != want[i] {
+			t.Errorf("span %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestIsSeekableFile(t *testing.T) {
+	if isSeekableFile("-") {
+		t.Error("stdin marker \"-\" should never be seekable")
+	}
+
+	plain := writeTempFile(t, "reads.fasta", ">r1\nACGT\n")
+	if !isSeekableFile(plain) {
+		t.Errorf("plain file %q should be seekable", plain)
+	}
+
+	gzByExt := filepath.Join(t.TempDir(), "reads.fasta.gz")
+	writeGzip(t, gzByExt, ">r1\nACGT\n")
+	if isSeekableFile(gzByExt) {
+		t.Errorf("gzip file %q should not be seekable (by extension)", gzByExt)
+	}
+
+	// same gzip bytes but without the tell-tale extension, as happens when
+	// stdin is materialized to a temp file: must be caught by magic-byte
+	// sniffing instead.
+	gzNoExt := filepath.Join(t.TempDir(), "seqkit-split-stdin-123")
+	writeGzip(t, gzNoExt, ">r1\nACGT\n")
+	if isSeekableFile(gzNoExt) {
+		t.Errorf("gzip file %q with no extension should still be detected as not seekable", gzNoExt)
+	}
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	fh, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer fh.Close()
+	zw := gzip.NewWriter(fh)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+}
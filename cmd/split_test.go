@@ -0,0 +1,59 @@
+// Copyright © 2016 Wei Shen <shenwei356@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import "testing"
+
+func TestMeanQual(t *testing.T) {
+	cases := []struct {
+		qual []byte
+		want float64
+	}{
+		{nil, 0},
+		{[]byte{}, 0},
+		{[]byte("IIII"), 40}, // 'I' = 73, 73-33 = 40
+		{[]byte("!"), 0},     // '!' = 33, 33-33 = 0
+		{[]byte("!I"), 20},   // (0+40)/2
+	}
+	for _, c := range cases {
+		if got := meanQual(c.qual); got != c.want {
+			t.Errorf("meanQual(%q) = %v, want %v", c.qual, got, c.want)
+		}
+	}
+}
+
+func TestFastqExt(t *testing.T) {
+	cases := []struct {
+		file string
+		want string
+	}{
+		{"reads.fq.gz", ".fq.gz"},
+		{"reads.fastq.gz", ".fastq.gz"},
+		{"READS.FQ", ".fq"},
+		{"reads.fastq", ".fastq"},
+		{"reads", ".fastq"},
+	}
+	for _, c := range cases {
+		if got := fastqExt(c.file); got != c.want {
+			t.Errorf("fastqExt(%q) = %q, want %q", c.file, got, c.want)
+		}
+	}
+}